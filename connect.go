@@ -0,0 +1,148 @@
+// See LICENSE file for copyright and license details.
+
+package wpasupplicant
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// A Network describes the SSID and credentials to connect to, for
+// ConnectSSID. PSK holds the WPA-PSK passphrase or, for a WPA3-SAE network,
+// the SAE password. It is ignored for an open network.
+type Network struct {
+	SSID string
+	PSK  string
+}
+
+// ConnectSSID scans, picks the strongest-signal BSS matching network.SSID,
+// configures a new network for it (auto-detecting open, WPA2-PSK, and
+// WPA3-SAE security from the BSS's advertised flags), selects it, and waits
+// for wpa_supplicant to report CTRL-EVENT-CONNECTED. The wait is bounded by
+// ctx; if ctx is cancelled, or the connection otherwise fails, the
+// half-configured network is removed before returning the error.
+func (c *Conn) ConnectSSID(ctx context.Context, network Network) (id int, err error) {
+	bss, err := c.BSSBySSID(network.SSID)
+	if err != nil {
+		return -1, err
+	}
+
+	if id, err = c.AddNetwork(); err != nil {
+		return -1, err
+	}
+
+	if err = c.configureNetworkForBSS(id, network, bss); err != nil {
+		c.RemoveNetwork(id)
+		return -1, err
+	}
+
+	if err = c.SelectNetwork(id); err != nil {
+		c.RemoveNetwork(id)
+		return -1, err
+	}
+
+	if err = c.waitForConnected(ctx, id); err != nil {
+		c.RemoveNetwork(id)
+		return -1, err
+	}
+
+	return id, nil
+}
+
+// configureNetworkForBSS sets the key_mgmt (and related) fields of network
+// id to match the security bss advertises.
+func (c *Conn) configureNetworkForBSS(id int, network Network, bss BSS) error {
+	if err := c.SetNetworkQuoted(id, "ssid", network.SSID); err != nil {
+		return err
+	}
+
+	switch {
+	case bss.IsOpen():
+		return c.SetNetwork(id, "key_mgmt", "NONE")
+
+	case bss.IsWPA3():
+		if err := c.SetNetwork(id, "key_mgmt", "SAE"); err != nil {
+			return err
+		}
+		if err := c.SetNetwork(id, "ieee80211w", "2"); err != nil {
+			return err
+		}
+		return c.SetNetworkQuoted(id, "sae_password", network.PSK)
+
+	case strings.Contains(bss.KeyMgmt(), "EAP"):
+		return c.SetNetwork(id, "key_mgmt", "WPA-EAP")
+
+	default:
+		if err := c.SetNetwork(id, "key_mgmt", "WPA-PSK"); err != nil {
+			return err
+		}
+		return c.SetNetworkQuoted(id, "psk", network.PSK)
+	}
+}
+
+// maxConnectDisconnects bounds how many CTRL-EVENT-DISCONNECTED events
+// waitForConnected tolerates while waiting for a connection: wpa_supplicant
+// retries a few times on a wrong PSK before giving up, and we'd otherwise
+// block until ctx expires instead of reporting the likely cause.
+const maxConnectDisconnects = 3
+
+// waitForConnected blocks until wpa_supplicant reports CTRL-EVENT-CONNECTED
+// for network id, or ctx is done. It also watches for events indicating the
+// connection attempt has failed (a disabled or not-found network, or
+// repeated disconnects, usually from a wrong PSK/password) so callers get a
+// descriptive error instead of waiting out the full ctx deadline.
+func (c *Conn) waitForConnected(ctx context.Context, id int) error {
+	// subscribe (not Subscribe) so we get our own channel: a caller that's
+	// also watching events via Subscribe must not have them stolen by us.
+	events, err := c.subscribe()
+	if err != nil {
+		return err
+	}
+	defer c.unsubscribe(events)
+
+	disconnects := 0
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return fmt.Errorf("wpasupplicant: event channel closed while waiting to connect")
+			}
+			switch {
+			case ev.Type == EventConnected && ev.NetworkID == id:
+				return nil
+			case ev.Type == EventSSIDTempDisable && ev.NetworkID == id:
+				return fmt.Errorf("wpasupplicant: network %d was disabled while connecting (wrong credentials?): %s", id, ev.Raw)
+			case ev.Type == EventNetworkNotFound:
+				return fmt.Errorf("wpasupplicant: network not found while connecting: %s", ev.Raw)
+			case ev.Type == EventDisconnected:
+				disconnects++
+				if disconnects >= maxConnectDisconnects {
+					return fmt.Errorf("wpasupplicant: gave up after %d disconnects while connecting to network %d (wrong credentials?)", disconnects, id)
+				}
+			}
+		case <-ctx.Done():
+			return ctx.Err()
+		}
+	}
+}
+
+// Forget removes a network and saves the configuration.
+func (c *Conn) Forget(id int) error {
+	if err := c.RemoveNetwork(id); err != nil {
+		return err
+	}
+	return c.SaveConfig()
+}
+
+// UpdateNetwork sets each given field on network id and saves the
+// configuration. Values are sent as-is, so quote them yourself with
+// fmt.Sprintf("%q", ...) where SetNetworkQuoted would otherwise apply.
+func (c *Conn) UpdateNetwork(id int, fields map[string]string) error {
+	for field, value := range fields {
+		if err := c.SetNetwork(id, field, value); err != nil {
+			return err
+		}
+	}
+	return c.SaveConfig()
+}
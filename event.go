@@ -0,0 +1,315 @@
+// See LICENSE file for copyright and license details.
+
+package wpasupplicant
+
+import (
+	"fmt"
+	"io/ioutil"
+	"net"
+	"os"
+	"strconv"
+	"strings"
+)
+
+// An EventType identifies the kind of unsolicited message wpa_supplicant
+// sent on the monitor socket.
+type EventType string
+
+// Event types emitted by wpa_supplicant. This is not an exhaustive list of
+// every CTRL-EVENT-* string wpa_supplicant can produce, just the ones
+// callers most commonly need to act on.
+const (
+	EventConnected       EventType = "CTRL-EVENT-CONNECTED"
+	EventDisconnected    EventType = "CTRL-EVENT-DISCONNECTED"
+	EventScanResults     EventType = "CTRL-EVENT-SCAN-RESULTS"
+	EventScanStarted     EventType = "CTRL-EVENT-SCAN-STARTED"
+	EventSSIDTempDisable EventType = "CTRL-EVENT-SSID-TEMP-DISABLED"
+	EventNetworkNotFound EventType = "CTRL-EVENT-NETWORK-NOT-FOUND"
+	EventTerminating     EventType = "CTRL-EVENT-TERMINATING"
+	EventWPSSuccess      EventType = "WPS-SUCCESS"
+	EventWPSFail         EventType = "WPS-FAIL"
+	EventAPStaConnected  EventType = "AP-STA-CONNECTED"
+	EventAPStaDisconnect EventType = "AP-STA-DISCONNECTED"
+
+	// EventCtrlReq marks an interactive EAP credential request. Field,
+	// NetworkID and Text are populated; answer it with AnswerRequest.
+	EventCtrlReq EventType = "CTRL-REQ"
+)
+
+// An Event is a parsed unsolicited message from wpa_supplicant. Priority is
+// the syslog-style priority the message was tagged with (0 is the most
+// severe), Type is the leading CTRL-EVENT-*/WPS-*/AP-STA-* token, and Raw is
+// the whole message with the priority prefix removed (Type token included),
+// unparsed. The remaining fields are filled in on a best-effort basis
+// depending on Type; a zero value means the event didn't carry that piece of
+// information, except for NetworkID, which is -1 in that case.
+type Event struct {
+	Priority   int
+	Type       EventType
+	Raw        string
+	BSSID      net.HardwareAddr
+	SSID       string
+	ReasonCode int
+	Signal     int
+	NetworkID  int
+
+	// Field and Text are only set for an EventCtrlReq: Field is the
+	// credential being requested (e.g. "IDENTITY", "PASSWORD", "OTP") and
+	// Text is the human-readable prompt, e.g. "Identity needed for SSID
+	// foo".
+	Field string
+	Text  string
+}
+
+// parseEvent parses a single line received on the monitor socket, of the
+// form "<3>CTRL-EVENT-CONNECTED - Connection to 00:11:22:33:44:55 completed
+// [id=0 id_str=]".
+func parseEvent(line string) Event {
+	priority := 0
+	if strings.HasPrefix(line, "<") {
+		if end := strings.Index(line, ">"); end > 0 {
+			if p, err := strconv.Atoi(line[1:end]); err == nil {
+				priority = p
+			}
+			line = line[end+1:]
+		}
+	}
+
+	if strings.HasPrefix(line, "CTRL-REQ-") {
+		ev := parseCtrlReq(line)
+		ev.Priority = priority
+		return ev
+	}
+
+	ev := Event{Raw: line, Priority: priority, NetworkID: -1}
+
+	fields := strings.Fields(line)
+	if len(fields) == 0 {
+		return ev
+	}
+	ev.Type = EventType(fields[0])
+	ev.Raw = line
+
+	for _, field := range fields[1:] {
+		// Fields like "[id=0" and "id_str=]" come from a bracketed group
+		// ("[id=0 id_str=]") that strings.Fields splits apart; strip the
+		// brackets before matching so the prefix checks below still fire.
+		field = strings.Trim(field, "[]")
+		switch {
+		case strings.HasPrefix(field, "bssid="):
+			ev.BSSID, _ = net.ParseMAC(strings.TrimPrefix(field, "bssid="))
+		case isMACAddress(field):
+			ev.BSSID, _ = net.ParseMAC(field)
+		case strings.HasPrefix(field, "ssid="):
+			ev.SSID = strings.TrimPrefix(field, "ssid=")
+		case strings.HasPrefix(field, "reason="):
+			ev.ReasonCode, _ = strconv.Atoi(strings.TrimPrefix(field, "reason="))
+		case strings.HasPrefix(field, "signal="):
+			ev.Signal, _ = strconv.Atoi(strings.TrimPrefix(field, "signal="))
+		case strings.HasPrefix(field, "id="):
+			ev.NetworkID, _ = strconv.Atoi(strings.TrimPrefix(field, "id="))
+		}
+	}
+
+	return ev
+}
+
+// parseCtrlReq parses an interactive credential request, of the form
+// "CTRL-REQ-IDENTITY-1:Identity needed for SSID 'foo'".
+func parseCtrlReq(line string) Event {
+	ev := Event{Type: EventCtrlReq, Raw: line, NetworkID: -1}
+
+	rest := strings.TrimPrefix(line, "CTRL-REQ-")
+	colon := strings.IndexByte(rest, ':')
+	if colon < 0 {
+		return ev
+	}
+	head := rest[:colon]
+	ev.Text = rest[colon+1:]
+
+	dash := strings.LastIndexByte(head, '-')
+	if dash < 0 {
+		return ev
+	}
+	ev.Field = head[:dash]
+	ev.NetworkID, _ = strconv.Atoi(head[dash+1:])
+
+	return ev
+}
+
+// AnswerRequest answers an EventCtrlReq by sending a 'CTRL-RSP-<field>-<id>'
+// request carrying value, e.g. an identity, password, or OTP for an
+// interactive WPA-EAP network.
+func (c *Conn) AnswerRequest(id int, field, value string) error {
+	return c.sendRequestOk(fmt.Sprintf("CTRL-RSP-%s-%d:%s", field, id, value))
+}
+
+// isMACAddress reports whether field looks like a bare MAC address, as
+// found unlabelled in messages like "Connection to 00:11:22:33:44:55
+// completed".
+func isMACAddress(field string) bool {
+	_, err := net.ParseMAC(field)
+	return err == nil
+}
+
+// Subscribe opens a second control socket, attaches it to wpa_supplicant
+// with ATTACH, and returns a channel on which parsed unsolicited events are
+// delivered. The primary socket is left free for synchronous requests, so
+// callers can keep issuing e.g. Status() while events flow independently.
+//
+// Subscribe can be called more than once (including internally, by Scan and
+// ConnectSSID): each call gets its own channel fed from the same monitor
+// socket, so callers don't steal events from one another.
+func (c *Conn) Subscribe() (<-chan Event, error) {
+	return c.subscribe()
+}
+
+// subscribe is the unexported implementation behind Subscribe. It is also
+// used internally by Scan and ConnectSSID so that they can wait for events
+// of their own without consuming from a channel the caller may also be
+// reading from.
+func (c *Conn) subscribe() (chan Event, error) {
+	if !c.ok() {
+		return nil, fmt.Errorf("wpasupplicant: not connected")
+	}
+
+	c.evMu.Lock()
+	defer c.evMu.Unlock()
+
+	if c.monconn == nil {
+		if err := c.attachLocked(); err != nil {
+			return nil, err
+		}
+	}
+
+	ch := make(chan Event, 16)
+	c.subs[ch] = struct{}{}
+	return ch, nil
+}
+
+// attachLocked dials the monitor socket, issues ATTACH, and starts the
+// goroutine that reads and fans out events. c.evMu must be held.
+func (c *Conn) attachLocked() error {
+	local, err := ioutil.TempFile("/tmp", "wpa_supplicant_mon")
+	if err != nil {
+		return err
+	}
+	os.Remove(local.Name())
+
+	monconn, err := net.DialUnix("unixgram",
+		&net.UnixAddr{Name: local.Name(), Net: "unixgram"},
+		&net.UnixAddr{Name: c.rsock, Net: "unixgram"})
+	if err != nil {
+		return err
+	}
+
+	if _, err = monconn.Write([]byte("ATTACH")); err != nil {
+		monconn.Close()
+		return err
+	}
+	reply := make([]byte, 4096)
+	n, err := monconn.Read(reply)
+	if err != nil {
+		monconn.Close()
+		return err
+	}
+	if err = checkReplyOk(reply[:n]); err != nil {
+		monconn.Close()
+		return fmt.Errorf("wpasupplicant: ATTACH failed: %v", err)
+	}
+
+	c.monconn = monconn
+	c.monSock = local.Name()
+	c.subs = make(map[chan Event]struct{})
+
+	go c.readEvents(monconn)
+
+	return nil
+}
+
+// unsubscribe removes ch from the set of subscribers fed by readEvents and
+// closes it. It is the internal counterpart used by Scan and ConnectSSID to
+// stop listening without tearing down the monitor socket or any other
+// subscriber's channel.
+func (c *Conn) unsubscribe(ch chan Event) {
+	c.evMu.Lock()
+	defer c.evMu.Unlock()
+
+	if _, ok := c.subs[ch]; ok {
+		delete(c.subs, ch)
+		close(ch)
+	}
+}
+
+// readEvents reads datagrams from monconn until it is closed, parsing and
+// fanning out each one to every subscriber channel. monconn is passed in
+// (rather than read from c.monconn) so that, once a concurrent
+// Unsubscribe/Close has closed it, teardownMonitor can check whether
+// c.monconn still refers to this socket before clearing it.
+func (c *Conn) readEvents(monconn *net.UnixConn) {
+	for {
+		data, err := recvDatagram(monconn)
+		if err != nil {
+			c.teardownMonitor(monconn)
+			return
+		}
+		line := strings.TrimRight(string(data), "\n")
+		if line == "" {
+			continue
+		}
+		c.broadcast(parseEvent(line))
+	}
+}
+
+// broadcast delivers ev to every current subscriber, dropping it for any
+// subscriber whose buffer is full rather than blocking the others.
+func (c *Conn) broadcast(ev Event) {
+	c.evMu.Lock()
+	defer c.evMu.Unlock()
+
+	for ch := range c.subs {
+		select {
+		case ch <- ev:
+		default:
+		}
+	}
+}
+
+// teardownMonitor closes every subscriber channel and clears the monitor
+// state, once monconn has stopped producing events. It's a no-op if a newer
+// attachLocked has already replaced monconn by the time it runs.
+func (c *Conn) teardownMonitor(monconn *net.UnixConn) {
+	c.evMu.Lock()
+	if c.monconn != monconn {
+		c.evMu.Unlock()
+		return
+	}
+	subs := c.subs
+	monSock := c.monSock
+	c.subs = nil
+	c.monconn = nil
+	c.monSock = ""
+	c.evMu.Unlock()
+
+	for ch := range subs {
+		close(ch)
+	}
+	os.Remove(monSock)
+}
+
+// Unsubscribe sends DETACH and closes the monitor socket, which stops
+// delivery on every channel returned by Subscribe (including ones held
+// internally by in-flight Scan/ConnectSSID calls).
+func (c *Conn) Unsubscribe() error {
+	c.evMu.Lock()
+	monconn := c.monconn
+	c.evMu.Unlock()
+
+	if monconn == nil {
+		return nil
+	}
+
+	_, err := monconn.Write([]byte("DETACH"))
+	monconn.Close()
+	return err
+}
@@ -0,0 +1,82 @@
+// See LICENSE file for copyright and license details.
+
+package wpasupplicant
+
+import (
+	"fmt"
+	"path/filepath"
+	"strings"
+)
+
+// A Manager is a connection to wpa_supplicant's global control socket. It
+// lets one process add, remove, and connect to the per-interface sockets
+// living under ctrlDir, mirroring the CreateInterface/RemoveInterface/
+// GetInterface object model from wpa_supplicant's D-Bus API.
+type Manager struct {
+	conn    *Conn
+	ctrlDir string
+}
+
+// An InterfaceConfig describes the parameters of an 'INTERFACE_ADD' request.
+type InterfaceConfig struct {
+	Ifname        string
+	ConfFile      string
+	Driver        string
+	CtrlInterface string
+	Bridge        string
+}
+
+// NewManager connects to wpa_supplicant's global control socket at
+// globalSock. ctrlDir is the directory wpa_supplicant places its
+// per-interface control sockets in, and is used by GetInterface.
+func NewManager(globalSock, ctrlDir string) (*Manager, error) {
+	conn, err := Connect(globalSock)
+	if err != nil {
+		return nil, err
+	}
+	return &Manager{conn: conn, ctrlDir: ctrlDir}, nil
+}
+
+// Close closes the connection to the global control socket.
+func (m *Manager) Close() error {
+	return m.conn.Close()
+}
+
+// Interfaces returns the names of the interfaces wpa_supplicant currently
+// manages.
+func (m *Manager) Interfaces() ([]string, error) {
+	reply, err := m.conn.Interfaces()
+	if err != nil {
+		return nil, err
+	}
+
+	var ifaces []string
+	for _, line := range strings.Split(strings.TrimRight(reply, "\n"), "\n") {
+		if line != "" {
+			ifaces = append(ifaces, line)
+		}
+	}
+	return ifaces, nil
+}
+
+// AddInterface brings up a new interface under wpa_supplicant's control and
+// returns a Conn to it.
+func (m *Manager) AddInterface(cfg InterfaceConfig) (*Conn, error) {
+	cmd := fmt.Sprintf("INTERFACE_ADD %s\t%s\t%s\t%s\t\t%s",
+		cfg.Ifname, cfg.ConfFile, cfg.Driver, cfg.CtrlInterface, cfg.Bridge)
+	if err := m.conn.sendRequestOk(cmd); err != nil {
+		return nil, err
+	}
+	return m.GetInterface(cfg.Ifname)
+}
+
+// RemoveInterface removes an interface from wpa_supplicant's control.
+func (m *Manager) RemoveInterface(ifname string) error {
+	return m.conn.sendRequestOk(fmt.Sprintf("INTERFACE_REMOVE %s", ifname))
+}
+
+// GetInterface opens a Conn to the control socket of an interface
+// wpa_supplicant already manages.
+func (m *Manager) GetInterface(ifname string) (*Conn, error) {
+	return Connect(filepath.Join(m.ctrlDir, ifname))
+}
@@ -0,0 +1,206 @@
+// See LICENSE file for copyright and license details.
+
+package wpasupplicant
+
+import (
+	"fmt"
+	"net"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// scanTimeout bounds how long Scan waits for wpa_supplicant to report that
+// a scan it triggered has finished.
+const scanTimeout = 10 * time.Second
+
+// A BSS describes one access point found in a scan, as reported by
+// wpa_supplicant's 'BSS <id>' request.
+type BSS struct {
+	BSSID        net.HardwareAddr
+	Frequency    int
+	SignalLevel  int
+	SSID         string
+	Capabilities int
+	Flags        []string
+}
+
+// KeyMgmt returns the key management scheme advertised in Flags, e.g.
+// "WPA2-PSK-CCMP" or "WPA3-SAE-CCMP". It returns "" for an open network.
+func (b BSS) KeyMgmt() string {
+	for _, flag := range b.Flags {
+		if strings.HasPrefix(flag, "WPA") {
+			return flag
+		}
+	}
+	return ""
+}
+
+// IsWPA2 reports whether the BSS advertises WPA2 key management.
+func (b BSS) IsWPA2() bool {
+	return strings.Contains(b.KeyMgmt(), "WPA2")
+}
+
+// IsWPA3 reports whether the BSS advertises WPA3-SAE key management.
+func (b BSS) IsWPA3() bool {
+	return strings.Contains(b.KeyMgmt(), "WPA3") || strings.Contains(b.KeyMgmt(), "SAE")
+}
+
+// IsOpen reports whether the BSS advertises no key management at all.
+func (b BSS) IsOpen() bool {
+	return b.KeyMgmt() == ""
+}
+
+// parseKeyValue parses a wpa_supplicant reply made up of "field=value"
+// lines into a map. Lines that don't contain '=' are ignored.
+func parseKeyValue(reply string) map[string]string {
+	fields := make(map[string]string)
+	for _, line := range strings.Split(reply, "\n") {
+		if eq := strings.IndexByte(line, '='); eq > 0 {
+			fields[line[:eq]] = line[eq+1:]
+		}
+	}
+	return fields
+}
+
+// parseFlags splits a flags string like "[WPA2-PSK-CCMP][ESS]" into
+// []string{"WPA2-PSK-CCMP", "ESS"}.
+func parseFlags(flags string) []string {
+	flags = strings.TrimSuffix(strings.TrimPrefix(flags, "["), "]")
+	if flags == "" {
+		return nil
+	}
+	return strings.Split(flags, "][")
+}
+
+// parseBSS turns the key=value reply to a 'BSS <id>' request into a BSS. It
+// returns an error if the reply doesn't describe a BSS at all, which is how
+// wpa_supplicant signals that the requested index doesn't exist.
+func parseBSS(reply string) (BSS, error) {
+	fields := parseKeyValue(reply)
+
+	bssid, ok := fields["bssid"]
+	if !ok {
+		return BSS{}, fmt.Errorf("wpasupplicant: no such BSS")
+	}
+
+	bss := BSS{SSID: fields["ssid"]}
+	bss.BSSID, _ = net.ParseMAC(bssid)
+	bss.Frequency, _ = strconv.Atoi(fields["freq"])
+	bss.SignalLevel, _ = strconv.Atoi(fields["level"])
+	bss.Flags = parseFlags(fields["flags"])
+	if capField, ok := fields["capabilities"]; ok {
+		capField = strings.TrimPrefix(capField, "0x")
+		if n, err := strconv.ParseInt(capField, 16, 64); err == nil {
+			bss.Capabilities = int(n)
+		}
+	}
+
+	return bss, nil
+}
+
+// Scan triggers a scan and blocks until wpa_supplicant reports that it has
+// finished, via the CTRL-EVENT-SCAN-RESULTS event. It always takes a
+// subscriber channel of its own, attaching the monitor socket first if no
+// one has yet.
+func (c *Conn) Scan() error {
+	// subscribe (not Subscribe) so we get our own channel: a caller that's
+	// also watching events via Subscribe must not have them stolen by us.
+	events, err := c.subscribe()
+	if err != nil {
+		return err
+	}
+	defer c.unsubscribe(events)
+
+	if err := c.sendRequestOk("SCAN"); err != nil {
+		return err
+	}
+
+	timeout := time.After(scanTimeout)
+	for {
+		select {
+		case ev, ok := <-events:
+			if !ok {
+				return fmt.Errorf("wpasupplicant: event channel closed while waiting for scan results")
+			}
+			if ev.Type == EventScanResults {
+				return nil
+			}
+		case <-timeout:
+			return fmt.Errorf("wpasupplicant: timed out waiting for scan results")
+		}
+	}
+}
+
+// ScanResults triggers a scan and returns the structured results.
+func (c *Conn) ScanResults() ([]BSS, error) {
+	if err := c.Scan(); err != nil {
+		return nil, err
+	}
+	return c.bssList()
+}
+
+// bssList iterates 'BSS 0', 'BSS 1', ... until wpa_supplicant runs out of
+// results.
+func (c *Conn) bssList() ([]BSS, error) {
+	var results []BSS
+
+	for i := 0; ; i++ {
+		reply, err := c.BSS(i)
+		if err != nil {
+			return nil, err
+		}
+
+		bss, err := parseBSS(reply)
+		if err != nil {
+			break
+		}
+		results = append(results, bss)
+	}
+
+	return results, nil
+}
+
+// BSSByBSSID scans and returns the BSS with the given BSSID.
+func (c *Conn) BSSByBSSID(bssid net.HardwareAddr) (BSS, error) {
+	results, err := c.ScanResults()
+	if err != nil {
+		return BSS{}, err
+	}
+
+	for _, bss := range results {
+		if bss.BSSID.String() == bssid.String() {
+			return bss, nil
+		}
+	}
+
+	return BSS{}, fmt.Errorf("wpasupplicant: no BSS found with BSSID %v", bssid)
+}
+
+// BSSBySSID scans and returns the strongest-signal BSS advertising the
+// given SSID.
+func (c *Conn) BSSBySSID(ssid string) (BSS, error) {
+	results, err := c.ScanResults()
+	if err != nil {
+		return BSS{}, err
+	}
+
+	var (
+		best  BSS
+		found bool
+	)
+	for _, bss := range results {
+		if bss.SSID != ssid {
+			continue
+		}
+		if !found || bss.SignalLevel > best.SignalLevel {
+			best = bss
+			found = true
+		}
+	}
+
+	if !found {
+		return BSS{}, fmt.Errorf("wpasupplicant: no BSS found with SSID %q", ssid)
+	}
+	return best, nil
+}
@@ -0,0 +1,91 @@
+// See LICENSE file for copyright and license details.
+
+package wpasupplicant
+
+import (
+	"strconv"
+	"strings"
+)
+
+// A Status holds the parsed reply to a 'STATUS' request.
+type Status struct {
+	WpaState       string
+	BSSID          string
+	SSID           string
+	IPAddress      string
+	KeyMgmt        string
+	PairwiseCipher string
+	Freq           int
+	Mode           string
+}
+
+// StatusMap sends a 'STATUS' request and returns the reply as a
+// field-to-value map, without picking out any particular fields.
+func (c *Conn) StatusMap() (map[string]string, error) {
+	reply, err := c.Status()
+	if err != nil {
+		return nil, err
+	}
+	return parseKeyValue(reply), nil
+}
+
+// StatusParsed sends a 'STATUS' request and returns the reply as a Status.
+func (c *Conn) StatusParsed() (Status, error) {
+	fields, err := c.StatusMap()
+	if err != nil {
+		return Status{}, err
+	}
+
+	status := Status{
+		WpaState:       fields["wpa_state"],
+		BSSID:          fields["bssid"],
+		SSID:           fields["ssid"],
+		IPAddress:      fields["ip_address"],
+		KeyMgmt:        fields["key_mgmt"],
+		PairwiseCipher: fields["pairwise_cipher"],
+		Mode:           fields["mode"],
+	}
+	status.Freq, _ = strconv.Atoi(fields["freq"])
+
+	return status, nil
+}
+
+// A NetworkEntry is one row of a 'LIST_NETWORKS' reply.
+type NetworkEntry struct {
+	ID    int
+	SSID  string
+	BSSID string
+	Flags []string
+}
+
+// ListNetworksParsed sends a 'LIST_NETWORKS' request and returns the
+// configured networks, skipping the header row.
+func (c *Conn) ListNetworksParsed() ([]NetworkEntry, error) {
+	reply, err := c.ListNetworks()
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []NetworkEntry
+	lines := strings.Split(strings.TrimRight(reply, "\n"), "\n")
+	for _, line := range lines[1:] {
+		if line == "" {
+			continue
+		}
+		cols := strings.Split(line, "\t")
+		if len(cols) < 3 {
+			continue
+		}
+		id, err := strconv.Atoi(cols[0])
+		if err != nil {
+			continue
+		}
+		entry := NetworkEntry{ID: id, SSID: cols[1], BSSID: cols[2]}
+		if len(cols) > 3 {
+			entry.Flags = parseFlags(cols[3])
+		}
+		entries = append(entries, entry)
+	}
+
+	return entries, nil
+}
@@ -40,6 +40,7 @@ import (
 	"os"
 	"strconv"
 	"strings"
+	"sync"
 	"syscall"
 )
 
@@ -56,6 +57,16 @@ const (
 type Conn struct {
 	uconn     *net.UnixConn
 	localSock string
+	rsock     string
+
+	// evMu guards monconn, monSock and subs, which are set by subscribe
+	// and torn down by Unsubscribe/teardownMonitor; see event.go. The
+	// monitor socket is read by its own goroutine, so these need a lock
+	// rather than being left to the caller's discipline.
+	evMu    sync.Mutex
+	monconn *net.UnixConn
+	monSock string
+	subs    map[chan Event]struct{}
 }
 
 func (c *Conn) ok() bool {
@@ -79,6 +90,7 @@ func Connect(rsock string) (*Conn, error) {
 	os.Remove(local.Name())
 
 	uc.localSock = local.Name()
+	uc.rsock = rsock
 	uc.uconn, err = net.DialUnix("unixgram",
 		&net.UnixAddr{Name: local.Name(), Net: "unixgram"},
 		&net.UnixAddr{Name: rsock, Net: "unixgram"})
@@ -89,6 +101,7 @@ func Connect(rsock string) (*Conn, error) {
 // Close the socket connection.
 func (c *Conn) Close() error {
 	var err error
+	c.Unsubscribe()
 	if c.ok() {
 		err = c.uconn.Close()
 	}
@@ -111,9 +124,63 @@ func (c *Conn) sendRequest(msg string) (reply []byte, err error) {
 	if n, err = c.uconn.Write([]byte(msg)); err != nil || n != len(msg) {
 		return reply, fmt.Errorf("Error sending request: %v", err)
 	}
-	reply = make([]byte, 4096)
-	n, err = c.uconn.Read(reply)
-	return reply[:n], err
+
+	return recvDatagram(c.uconn)
+}
+
+// recvDatagram reads one full datagram from uconn. Replies routinely exceed
+// a few KB (e.g. 'BSS RANGE=ALL', 'SCAN_RESULTS', verbose status), and a
+// plain Read on a unixgram socket silently truncates anything past the
+// buffer it's given with no way to detect that it happened. So we first
+// peek the datagram's real size with MSG_PEEK|MSG_TRUNC, which reports the
+// full length even when the peek buffer is smaller than it, then allocate
+// to fit before doing the real Read.
+func recvDatagram(uconn *net.UnixConn) ([]byte, error) {
+	size, err := peekDatagramSize(uconn)
+	if err != nil {
+		return nil, err
+	}
+	if size < 1 {
+		size = 1
+	}
+
+	buf := make([]byte, size)
+	n, err := uconn.Read(buf)
+	if err != nil {
+		return nil, err
+	}
+	return buf[:n], nil
+}
+
+// peekDatagramSize returns the size of the next datagram queued on uconn
+// without consuming it.
+func peekDatagramSize(uconn *net.UnixConn) (int, error) {
+	raw, err := uconn.SyscallConn()
+	if err != nil {
+		return 0, err
+	}
+
+	// The peek buffer itself can be tiny: MSG_TRUNC makes recvmsg report
+	// the datagram's real length regardless of how much of it fits.
+	probe := make([]byte, 1)
+	var (
+		n       int
+		recvErr error
+	)
+	ctrlErr := raw.Read(func(fd uintptr) bool {
+		n, _, _, _, recvErr = syscall.Recvmsg(int(fd), probe, nil, syscall.MSG_PEEK|syscall.MSG_TRUNC)
+		// Returning false tells the runtime to wait for the socket to
+		// become readable and call us again, rather than busy-looping or
+		// surfacing a transient EAGAIN as a hard error.
+		return recvErr != syscall.EAGAIN
+	})
+	if ctrlErr != nil {
+		return 0, ctrlErr
+	}
+	if recvErr != nil {
+		return 0, recvErr
+	}
+	return n, nil
 }
 
 // Check that a relpy message from wpa_supplicant was the 'OK' message.
@@ -179,7 +246,7 @@ func (c *Conn) GetNetwork(id int, field string) (value string, err error) {
 		return "", err
 	}
 
-	return string(reply), nil
+	return strings.TrimSpace(string(reply)), nil
 }
 
 // AddNetwork adds a new, empty network.
@@ -257,17 +324,11 @@ func (c *Conn) ListNetworks() (string, error) {
 
 // NumOfNetworks returns the number of networks configured.
 func (c *Conn) NumOfNetworks() (int, error) {
-	var (
-		reply string
-		err   error
-	)
-
-	if reply, err = c.ListNetworks(); err != nil {
+	entries, err := c.ListNetworksParsed()
+	if err != nil {
 		return 0, err
 	}
-
-	// we don't want to include the header in the count
-	return strings.Count(reply, "\n") - 1, nil
+	return len(entries), nil
 }
 
 // Reconfigure forces wpa_supplicant to re-read its configuration data.